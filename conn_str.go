@@ -1,6 +1,7 @@
 package mssql
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/url"
@@ -25,24 +26,42 @@ type connectParams struct {
 	conn_timeout              time.Duration
 	keepAlive                 time.Duration
 	encrypt                   bool
+	encryptProvided           bool
 	disableEncryption         bool
 	trustServerCertificate    bool
 	certificate               string
 	hostInCertificate         string
 	hostInCertificateProvided bool
-	serverSPN                 string
-	workstation               string
-	appname                   string
-	typeFlags                 uint8
-	failOverPartner           string
-	failOverPort              uint64
-	packetSize                uint16
-	fedAuthLibrary            int
-	fedAuthADALWorkflow       byte
-	columnEncryption          bool
-	keyStoreAuthentication    KeyStoreAuthentication
-	keyStoreLocation          string
-	keyStoreSecret            string
+	// tlsConfig, when set via the `tls` DSN parameter and RegisterTLSConfig,
+	// takes precedence over certificate/hostInCertificate/trustServerCertificate
+	// during the PRELOGIN/handshake TLS setup.
+	tlsConfig              *tls.Config
+	tlsConfigName          string
+	serverSPN              string
+	workstation            string
+	appname                string
+	typeFlags              uint8
+	failOverPartner        string
+	failOverPort           uint64
+	packetSize             uint16
+	fedAuthLibrary         int
+	fedAuthADALWorkflow    byte
+	columnEncryption       bool
+	keyStoreAuthentication KeyStoreAuthentication
+	keyStoreLocation       string
+	keyStoreSecret         string
+	retryCount             int
+	retryInterval          time.Duration
+	retryBackoff           BackoffStrategy
+	retryTxn               bool
+	multiSubnetFailover    bool
+	resolver               Resolver
+	// dialer, accessTokenProvider and columnEncryptionKeyProvider have no DSN
+	// string representation; they can only be set by going through the
+	// programmatic Config/NewConnector path.
+	dialer                      Dialer
+	accessTokenProvider         AccessTokenProvider
+	columnEncryptionKeyProvider ColumnEncryptionKeyProvider
 }
 
 // default packet size for TDS buffer
@@ -166,6 +185,7 @@ func parseConnectParams(dsn string) (connectParams, error) {
 	}
 	encrypt, ok := params["encrypt"]
 	if ok {
+		p.encryptProvided = true
 		if strings.EqualFold(encrypt, "DISABLE") {
 			p.disableEncryption = true
 		} else {
@@ -245,6 +265,27 @@ func parseConnectParams(dsn string) (connectParams, error) {
 		p.hostInCertificateProvided = false
 	}
 
+	tlsParam, ok := params["tls"]
+	if ok {
+		p.tlsConfigName = tlsParam
+		switch {
+		case strings.EqualFold(tlsParam, "true"):
+			p.encrypt = true
+		case strings.EqualFold(tlsParam, "false"):
+			p.encrypt = false
+		case strings.EqualFold(tlsParam, "skip-verify"):
+			p.encrypt = true
+			p.trustServerCertificate = true
+		default:
+			cfg, found := getTLSConfig(tlsParam)
+			if !found {
+				return p, fmt.Errorf("invalid tls '%s': no tls.Config registered with that name", tlsParam)
+			}
+			p.encrypt = true
+			p.tlsConfig = cfg
+		}
+	}
+
 	serverSPN, ok := params["serverspn"]
 	if ok {
 		p.serverSPN = serverSPN
@@ -293,11 +334,63 @@ func parseConnectParams(dsn string) (connectParams, error) {
 		}
 	}
 
+	p.retryBackoff = BackoffConstant
+	retryBackoff, ok := params["retrybackoff"]
+	if ok {
+		switch strings.ToLower(retryBackoff) {
+		case "constant":
+			p.retryBackoff = BackoffConstant
+		case "exponential":
+			p.retryBackoff = BackoffExponential
+		default:
+			return p, fmt.Errorf("invalid retrybackoff '%s': must be 'constant' or 'exponential'", retryBackoff)
+		}
+	}
+
+	retryCount, ok := params["retrycount"]
+	if ok {
+		var err error
+		p.retryCount, err = strconv.Atoi(retryCount)
+		if err != nil || p.retryCount < 0 {
+			return p, fmt.Errorf("invalid retrycount '%s': must be a non-negative integer", retryCount)
+		}
+	}
+
+	p.retryInterval = 100 * time.Millisecond
+	retryInterval, ok := params["retryinterval"]
+	if ok {
+		interval, err := time.ParseDuration(retryInterval)
+		if err != nil {
+			return p, fmt.Errorf("invalid retryinterval '%s': %s", retryInterval, err.Error())
+		}
+		p.retryInterval = interval
+	}
+
+	retryTxn, ok := params["retrytxn"]
+	if ok {
+		var err error
+		p.retryTxn, err = strconv.ParseBool(retryTxn)
+		if err != nil {
+			return p, fmt.Errorf("invalid retrytxn '%s': %s", retryTxn, err.Error())
+		}
+	}
+
+	multiSubnetFailover, ok := params["multisubnetfailover"]
+	if ok {
+		var err error
+		p.multiSubnetFailover, err = strconv.ParseBool(multiSubnetFailover)
+		if err != nil {
+			return p, fmt.Errorf("invalid multisubnetfailover '%s': %s", multiSubnetFailover, err.Error())
+		}
+	}
+
 	return p, nil
 }
 
-// convert connectionParams to url style connection string
-// used mostly for testing
+// convert connectionParams to url style connection string.
+// Used by tests, and backs the public Config.FormatDSN round-trip, so it
+// must serialize every DSN-representable field, not just the ones tests
+// happened to need.
 func (p connectParams) toUrl() *url.URL {
 	q := url.Values{}
 	if p.database != "" {
@@ -323,9 +416,102 @@ func (p connectParams) toUrl() *url.URL {
 		q.Add("keyStoreSecret", p.keyStoreSecret)
 	}
 
+	if p.tlsConfigName != "" {
+		q.Add("tls", p.tlsConfigName)
+	}
+
+	if p.retryCount != 0 {
+		q.Add("retryCount", strconv.Itoa(p.retryCount))
+	}
+
+	if p.retryInterval != 100*time.Millisecond {
+		q.Add("retryInterval", p.retryInterval.String())
+	}
+
+	if p.retryBackoff == BackoffExponential {
+		q.Add("retryBackoff", "exponential")
+	}
+
+	if p.retryTxn {
+		q.Add("retryTxn", "true")
+	}
+
+	if p.multiSubnetFailover {
+		q.Add("multiSubnetFailover", "true")
+	}
+
+	if p.disableEncryption {
+		q.Add("encrypt", "DISABLE")
+	} else if p.encrypt {
+		q.Add("encrypt", "true")
+	} else if p.encryptProvided || !p.trustServerCertificate {
+		// An explicit encrypt=false, or trustServerCertificate=false without
+		// one, must round-trip as explicit, not as "absent" - absent reparsed
+		// through parseConnectParams means trustServerCertificate defaults to
+		// true, which would silently flip on reparse otherwise.
+		q.Add("encrypt", "false")
+	}
+
+	if p.trustServerCertificate {
+		q.Add("trustServerCertificate", "true")
+	}
+
+	if p.certificate != "" {
+		q.Add("certificate", p.certificate)
+	}
+
+	if p.hostInCertificateProvided {
+		q.Add("hostNameInCertificate", p.hostInCertificate)
+	}
+
+	if p.workstation != "" {
+		q.Add("workstation id", p.workstation)
+	}
+
+	if p.appname != "" {
+		q.Add("app name", p.appname)
+	}
+
+	if p.serverSPN != "" {
+		q.Add("serverSPN", p.serverSPN)
+	}
+
+	if p.dial_timeout != 15*time.Second {
+		q.Add("dial timeout", strconv.FormatInt(int64(p.dial_timeout/time.Second), 10))
+	}
+
+	if p.conn_timeout != 0 {
+		q.Add("connection timeout", strconv.FormatInt(int64(p.conn_timeout/time.Second), 10))
+	}
+
+	if p.keepAlive != 30*time.Second {
+		q.Add("keepalive", strconv.FormatInt(int64(p.keepAlive/time.Second), 10))
+	}
+
+	if p.failOverPartner != "" {
+		q.Add("failoverpartner", p.failOverPartner)
+	}
+
+	if p.failOverPort != 0 {
+		q.Add("failoverport", strconv.FormatUint(p.failOverPort, 10))
+	}
+
+	if p.packetSize != 0 && p.packetSize != defaultPacketSize {
+		q.Add("packet size", strconv.FormatUint(uint64(p.packetSize), 10))
+	}
+
+	if p.typeFlags&fReadOnlyIntent != 0 {
+		q.Add("applicationintent", "ReadOnly")
+	}
+
+	host := p.host
+	if p.port != 0 {
+		host = net.JoinHostPort(p.host, strconv.FormatUint(p.port, 10))
+	}
+
 	res := url.URL{
 		Scheme: "sqlserver",
-		Host:   p.host,
+		Host:   host,
 		User:   url.UserPassword(p.user, p.password),
 	}
 	if p.instance != "" {
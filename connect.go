@@ -0,0 +1,173 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Conn implements driver.Conn for an established connection to SQL Server.
+type Conn struct {
+	sess   net.Conn
+	params connectParams
+
+	// accessToken is the token obtained from params.accessTokenProvider
+	// during connect, when AAD federated authentication is configured.
+	accessToken string
+}
+
+// Prepare is not implemented by this build of the driver; statement
+// preparation and execution live in the TDS request/response layer, which is
+// out of scope for the connection-establishment work done here.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("mssql: Prepare is not implemented in this build")
+}
+
+// Close closes the underlying network connection.
+func (c *Conn) Close() error {
+	return c.sess.Close()
+}
+
+// Begin is not implemented by this build of the driver.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("mssql: Begin is not implemented in this build")
+}
+
+// QueryContext implements driver.QueryerContext, transparently retrying
+// query execution per the connection's retry Policy (c.params' DSN/Config
+// defaults, overridable per call with WithRetryPolicy). This is the
+// interception point requested for transient SQL Server error retries.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	policy := retryPolicyFromContext(ctx, policyFromConnectParams(c.params))
+
+	var rows driver.Rows
+	err := withRetry(ctx, policy, func() error {
+		var err error
+		rows, err = c.queryOnce(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+// ExecContext implements driver.ExecerContext, transparently retrying
+// statement execution per the connection's retry Policy, the same way
+// QueryContext does.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	policy := retryPolicyFromContext(ctx, policyFromConnectParams(c.params))
+
+	var result driver.Result
+	err := withRetry(ctx, policy, func() error {
+		var err error
+		result, err = c.execOnce(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+// queryOnce and execOnce are a single, non-retried attempt at running query.
+// They are not implemented by this build: submitting a request and streaming
+// its results is done by the TDS request/response layer, which is out of
+// scope for the connection-establishment and retry-policy work done here.
+// withRetry re-runs these whenever RetryableError reports the failure as
+// transient. A failed attempt hands no rows to the caller, and Conn has no
+// transactions (Begin is not implemented), so Policy.RetryTxn is not
+// consulted here.
+func (c *Conn) queryOnce(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return nil, fmt.Errorf("mssql: query execution is not implemented in this build")
+}
+
+func (c *Conn) execOnce(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return nil, fmt.Errorf("mssql: statement execution is not implemented in this build")
+}
+
+// decryptColumnEncryptionKey resolves the plaintext of an Always Encrypted
+// column key through params.columnEncryptionKeyProvider. This build has no
+// built-in key-store-backed resolution, so without a provider it returns an
+// error.
+func (c *Conn) decryptColumnEncryptionKey(ctx context.Context, masterKeyPath, algorithm string, encryptedKey []byte) ([]byte, error) {
+	if c.params.columnEncryptionKeyProvider != nil {
+		return c.params.columnEncryptionKeyProvider.DecryptColumnEncryptionKey(ctx, masterKeyPath, algorithm, encryptedKey)
+	}
+	return nil, fmt.Errorf("mssql: no ColumnEncryptionKeyProvider configured and no built-in key store implemented in this build")
+}
+
+// connect establishes a new connection to SQL Server using p and returns it
+// ready for use as a driver.Conn.
+func connect(ctx context.Context, p connectParams) (*Conn, error) {
+	sess, err := dialConnection(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.encrypt && !p.disableEncryption {
+		sess, err = upgradeToTLS(ctx, sess, p)
+		if err != nil {
+			sess.Close()
+			return nil, err
+		}
+	}
+
+	var accessToken string
+	if p.accessTokenProvider != nil {
+		accessToken, err = p.accessTokenProvider(ctx)
+		if err != nil {
+			sess.Close()
+			return nil, fmt.Errorf("mssql: fetching access token: %w", err)
+		}
+	}
+
+	return &Conn{sess: sess, params: p, accessToken: accessToken}, nil
+}
+
+// dialConnection opens the TCP connection used by connect. When
+// multiSubnetFailover is set, every address behind p.host races in parallel
+// with first-connect-wins semantics, as required for AlwaysOn availability
+// group listeners. Otherwise it dials the primary host and falls back to
+// failOverPartner/failOverPort serially on failure, as before.
+func dialConnection(ctx context.Context, p connectParams) (net.Conn, error) {
+	port := resolveServerPort(p.port)
+
+	if p.multiSubnetFailover {
+		return dialMultiSubnetFailover(ctx, p.resolver, p.dialer, p.host, port, p.dial_timeout)
+	}
+
+	conn, err := dialSingle(ctx, p.dialer, p.host, port, p.dial_timeout)
+	if err == nil {
+		return conn, nil
+	}
+	if p.failOverPartner == "" {
+		return nil, err
+	}
+
+	failOverPort := p.failOverPort
+	if failOverPort == 0 {
+		failOverPort = port
+	}
+	return dialSingle(ctx, p.dialer, p.failOverPartner, failOverPort, p.dial_timeout)
+}
+
+// dialSingle dials a single host:port, bounded by dialTimeout, and confirms
+// the server acknowledges the connection via the PRELOGIN exchange before
+// handing it back.
+func dialSingle(ctx context.Context, dialer Dialer, host string, port uint64, dialTimeout time.Duration) (net.Conn, error) {
+	dialCtx := ctx
+	if dialTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, dialTimeout)
+		defer cancel()
+	}
+
+	conn, err := dialContext(dialCtx, dialer, net.JoinHostPort(host, strconv.FormatUint(port, 10)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := preloginHandshake(dialCtx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
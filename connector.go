@@ -0,0 +1,274 @@
+package mssql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Dialer abstracts the network dial used to establish the TCP connection to
+// SQL Server, letting callers inject context-aware dialing behavior such as
+// a proxy, a service-mesh aware resolver, or custom retry/backoff.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// AccessTokenProvider returns a fresh Azure Active Directory access token for
+// the ActiveDirectoryToken federated authentication workflow. It is called on
+// every connection attempt, so it is the right place to implement token
+// caching, refresh or rotation.
+type AccessTokenProvider func(ctx context.Context) (string, error)
+
+// ColumnEncryptionKeyProvider resolves the plaintext value of an Always
+// Encrypted column encryption key from its encrypted form. It is the only
+// way column keys are decrypted in this build: the
+// keyStoreAuthentication/keyStoreLocation/keyStoreSecret parameters are
+// parsed but no built-in key store uses them.
+type ColumnEncryptionKeyProvider interface {
+	DecryptColumnEncryptionKey(ctx context.Context, masterKeyPath, algorithm string, encryptedKey []byte) ([]byte, error)
+}
+
+// Config holds the same connection information as a DSN, but as a plain Go
+// struct so callers can set values that have no string representation, such
+// as a *tls.Config, a Dialer, or an AccessTokenProvider. Build a
+// driver.Connector from it with NewConnector and pass that to sql.OpenDB, or
+// round-trip it to/from a DSN with FormatDSN/ParseDSN.
+type Config struct {
+	Host     string
+	Instance string
+	Port     uint64
+	Database string
+	User     string
+	Password string
+
+	DialTimeout time.Duration
+	ConnTimeout time.Duration
+	KeepAlive   time.Duration
+
+	Encrypt                bool
+	DisableEncryption      bool
+	TrustServerCertificate bool
+	Certificate            string
+	HostInCertificate      string
+	TLSConfig              *tls.Config
+
+	// encryptProvided records whether Encrypt was set explicitly by ParseDSN
+	// parsing an "encrypt=..." DSN parameter, as opposed to defaulted to its
+	// zero value. FormatDSN needs this to round-trip an explicit
+	// encrypt=false instead of reparsing it as "encrypt absent", which
+	// defaults TrustServerCertificate to true.
+	encryptProvided bool
+
+	ServerSPN   string
+	Workstation string
+	AppName     string
+	PacketSize  uint16
+
+	FailOverPartner     string
+	FailOverPort        uint64
+	MultiSubnetFailover bool
+
+	// Resolver overrides DNS resolution used by MultiSubnetFailover to
+	// enumerate the A/AAAA records of an AlwaysOn availability group
+	// listener. Nil means resolve with net.DefaultResolver. Tests can
+	// inject a fake implementation here.
+	Resolver Resolver
+
+	ColumnEncryption       bool
+	KeyStoreAuthentication KeyStoreAuthentication
+	KeyStoreLocation       string
+	KeyStoreSecret         string
+
+	// RetryPolicy is the default transient-error retry behavior for
+	// statements and transactions run over connections built from this
+	// Config. It can be overridden per call with WithRetryPolicy.
+	RetryPolicy Policy
+
+	// Dialer overrides the default dialer used to open the TCP connection.
+	// Nil means dial with a zero-value net.Dialer bounded by DialTimeout;
+	// KeepAlive is not applied to it.
+	Dialer Dialer
+
+	// AccessTokenProvider, when set, is used instead of User/Password to
+	// authenticate via Azure Active Directory federated authentication.
+	AccessTokenProvider AccessTokenProvider
+
+	// ColumnEncryptionKeyProvider, when set, decrypts Always Encrypted column
+	// keys. This build has no built-in key-store-backed provider.
+	ColumnEncryptionKeyProvider ColumnEncryptionKeyProvider
+}
+
+// connector adapts a Config into a driver.Connector.
+type connector struct {
+	cfg    Config
+	params connectParams
+}
+
+// NewConnector returns a driver.Connector built directly from cfg, bypassing
+// DSN string parsing entirely. The returned connector can be passed to
+// sql.OpenDB. Unlike a DSN, cfg can carry live objects such as a *tls.Config,
+// a Dialer or an AccessTokenProvider.
+func NewConnector(cfg *Config) (driver.Connector, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("mssql: Config must not be nil")
+	}
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("mssql: Config.Host must be set")
+	}
+
+	return &connector{cfg: *cfg, params: cfg.toConnectParams()}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return connect(ctx, c.params)
+}
+
+// toConnectParams folds a Config into the internal connectParams used by the
+// DSN-parsing code path, so both entry points share the same connection
+// logic downstream.
+func (cfg *Config) toConnectParams() connectParams {
+	p := connectParams{
+		host:                        cfg.Host,
+		instance:                    cfg.Instance,
+		port:                        cfg.Port,
+		database:                    cfg.Database,
+		user:                        cfg.User,
+		password:                    cfg.Password,
+		dial_timeout:                cfg.DialTimeout,
+		conn_timeout:                cfg.ConnTimeout,
+		keepAlive:                   cfg.KeepAlive,
+		encrypt:                     cfg.Encrypt,
+		encryptProvided:             cfg.encryptProvided,
+		disableEncryption:           cfg.DisableEncryption,
+		trustServerCertificate:      cfg.TrustServerCertificate,
+		certificate:                 cfg.Certificate,
+		hostInCertificate:           cfg.HostInCertificate,
+		tlsConfig:                   cfg.TLSConfig,
+		serverSPN:                   cfg.ServerSPN,
+		workstation:                 cfg.Workstation,
+		appname:                     cfg.AppName,
+		packetSize:                  cfg.PacketSize,
+		failOverPartner:             cfg.FailOverPartner,
+		failOverPort:                cfg.FailOverPort,
+		multiSubnetFailover:         cfg.MultiSubnetFailover,
+		resolver:                    cfg.Resolver,
+		fedAuthLibrary:              fedAuthLibraryReserved,
+		columnEncryption:            cfg.ColumnEncryption,
+		keyStoreAuthentication:      cfg.KeyStoreAuthentication,
+		keyStoreLocation:            cfg.KeyStoreLocation,
+		keyStoreSecret:              cfg.KeyStoreSecret,
+		retryCount:                  cfg.RetryPolicy.MaxRetries,
+		retryInterval:               cfg.RetryPolicy.Interval,
+		retryBackoff:                cfg.RetryPolicy.Backoff,
+		retryTxn:                    cfg.RetryPolicy.RetryTxn,
+		dialer:                      cfg.Dialer,
+		accessTokenProvider:         cfg.AccessTokenProvider,
+		columnEncryptionKeyProvider: cfg.ColumnEncryptionKeyProvider,
+	}
+	if cfg.HostInCertificate != "" {
+		p.hostInCertificateProvided = true
+	} else {
+		p.hostInCertificate = cfg.Host
+	}
+
+	// Apply the same non-boolean defaults parseConnectParams applies to a
+	// DSN, so a Config with unset fields does not silently get a 0s
+	// timeout/keepalive/retry interval. Boolean fields are taken as given:
+	// unlike a DSN without "encrypt", a Config does not default
+	// TrustServerCertificate to true.
+	if p.packetSize == 0 {
+		p.packetSize = defaultPacketSize
+	}
+	if p.dial_timeout == 0 {
+		p.dial_timeout = 15 * time.Second
+	}
+	if p.keepAlive == 0 {
+		p.keepAlive = 30 * time.Second
+	}
+	if p.retryInterval == 0 {
+		p.retryInterval = 100 * time.Millisecond
+	}
+	if p.workstation == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			p.workstation = hostname
+		}
+	}
+	if p.appname == "" {
+		p.appname = "go-mssqldb"
+	}
+	if p.serverSPN == "" {
+		p.serverSPN = generateSpn(p.host, resolveServerPort(p.port))
+	}
+	return p
+}
+
+// configFromConnectParams mirrors a connectParams back into the exported
+// Config shape, used by ParseDSN.
+func configFromConnectParams(p connectParams) *Config {
+	cfg := &Config{
+		Host:                        p.host,
+		Instance:                    p.instance,
+		Port:                        p.port,
+		Database:                    p.database,
+		User:                        p.user,
+		Password:                    p.password,
+		DialTimeout:                 p.dial_timeout,
+		ConnTimeout:                 p.conn_timeout,
+		KeepAlive:                   p.keepAlive,
+		Encrypt:                     p.encrypt,
+		encryptProvided:             p.encryptProvided,
+		DisableEncryption:           p.disableEncryption,
+		TrustServerCertificate:      p.trustServerCertificate,
+		Certificate:                 p.certificate,
+		TLSConfig:                   p.tlsConfig,
+		ServerSPN:                   p.serverSPN,
+		Workstation:                 p.workstation,
+		AppName:                     p.appname,
+		PacketSize:                  p.packetSize,
+		FailOverPartner:             p.failOverPartner,
+		FailOverPort:                p.failOverPort,
+		MultiSubnetFailover:         p.multiSubnetFailover,
+		Resolver:                    p.resolver,
+		ColumnEncryption:            p.columnEncryption,
+		KeyStoreAuthentication:      p.keyStoreAuthentication,
+		KeyStoreLocation:            p.keyStoreLocation,
+		KeyStoreSecret:              p.keyStoreSecret,
+		RetryPolicy:                 policyFromConnectParams(p),
+		Dialer:                      p.dialer,
+		AccessTokenProvider:         p.accessTokenProvider,
+		ColumnEncryptionKeyProvider: p.columnEncryptionKeyProvider,
+	}
+	if p.hostInCertificateProvided {
+		cfg.HostInCertificate = p.hostInCertificate
+	}
+	return cfg
+}
+
+// ParseDSN parses dsn the same way the sql.Open/sqlserver driver would and
+// returns the result as a Config, so callers can start from a DSN and then
+// override individual fields (TLSConfig, Dialer, ...) that have no string
+// representation before calling NewConnector.
+func ParseDSN(dsn string) (*Config, error) {
+	p, err := parseConnectParams(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return configFromConnectParams(p), nil
+}
+
+// FormatDSN renders cfg back into the serializable subset of a DSN string.
+// Fields with no string representation, such as TLSConfig, Dialer,
+// AccessTokenProvider and ColumnEncryptionKeyProvider, are omitted; use
+// NewConnector directly when those are set.
+func (cfg *Config) FormatDSN() string {
+	p := cfg.toConnectParams()
+	return p.toUrl().String()
+}
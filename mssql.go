@@ -0,0 +1,48 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// fedAuthLibraryReserved marks a connection as not using any federated
+// authentication library.
+const fedAuthLibraryReserved = 0
+
+// fReadOnlyIntent is the LOGIN7 OptionFlags3 bit requesting read-only
+// routing for ApplicationIntent=ReadOnly connections.
+const fReadOnlyIntent uint8 = 0x20
+
+func init() {
+	sql.Register("sqlserver", &Driver{})
+}
+
+// Driver implements database/sql/driver.Driver for the "sqlserver" dialect.
+type Driver struct{}
+
+// Open parses dsn and returns a new connection to SQL Server.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	params, err := parseConnectParams(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connect(context.Background(), params)
+}
+
+// Error represents an error reported by SQL Server itself, as opposed to a
+// network or driver-side failure.
+type Error struct {
+	Number     int32
+	State      uint8
+	Class      uint8
+	Message    string
+	ServerName string
+	ProcName   string
+	LineNo     int32
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("mssql: %s", e.Message)
+}
@@ -0,0 +1,127 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Resolver abstracts DNS resolution for MultiSubnetFailover, so tests and
+// callers can inject a fake implementation instead of net.DefaultResolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// defaultResolver adapts net.DefaultResolver to the Resolver interface.
+type defaultResolver struct{}
+
+func (defaultResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return net.DefaultResolver.LookupIPAddr(ctx, host)
+}
+
+// preloginHandshake performs the PRELOGIN exchange used to confirm the
+// server at the other end of conn has acknowledged the connection, not just
+// accepted the TCP handshake. It is a package variable rather than a plain
+// function so dialMultiSubnetFailover can race it across addresses and so
+// tests can substitute a fake; the production value is the driver's regular
+// PRELOGIN exchange used by the single-address dial path.
+var preloginHandshake func(ctx context.Context, conn net.Conn) error = realPreloginHandshake
+
+// dialMultiSubnetFailoverResult is what each parallel dial attempt reports
+// back on the race's result channel.
+type dialMultiSubnetFailoverResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialMultiSubnetFailover implements the first-connect-wins semantics SQL
+// Server clients use against AlwaysOn availability group listeners: host is
+// resolved to every A/AAAA record via resolver, each address is dialed in
+// parallel bounded by dialTimeout, and the winner is the first address whose
+// PRELOGIN exchange is acknowledged - not merely the first TCP handshake to
+// finish. Every other attempt, whether still dialing or already connected,
+// is cancelled/closed once a winner is found.
+func dialMultiSubnetFailover(ctx context.Context, resolver Resolver, dialer Dialer, host string, port uint64, dialTimeout time.Duration) (net.Conn, error) {
+	if resolver == nil {
+		resolver = defaultResolver{}
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("mssql: multisubnetfailover: resolving %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("mssql: multisubnetfailover: no addresses found for %s", host)
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialMultiSubnetFailoverResult, len(addrs))
+	for _, addr := range addrs {
+		addr := addr
+		go func() {
+			results <- attemptMultiSubnetFailoverDial(raceCtx, dialer, addr, port, dialTimeout)
+		}()
+	}
+
+	var firstErr error
+	for i := 0; i < len(addrs); i++ {
+		r := <-results
+		if r.err == nil {
+			cancel()
+			go drainMultiSubnetFailoverResults(results, len(addrs)-i-1)
+			return r.conn, nil
+		}
+		if firstErr == nil {
+			firstErr = r.err
+		}
+	}
+	return nil, fmt.Errorf("mssql: multisubnetfailover: all %d address(es) failed for %s: %w", len(addrs), host, firstErr)
+}
+
+// attemptMultiSubnetFailoverDial dials a single resolved address and runs
+// the PRELOGIN exchange against it, used as one racer of
+// dialMultiSubnetFailover.
+func attemptMultiSubnetFailoverDial(ctx context.Context, dialer Dialer, addr net.IPAddr, port uint64, dialTimeout time.Duration) dialMultiSubnetFailoverResult {
+	dialCtx := ctx
+	if dialTimeout > 0 {
+		var dialCancel context.CancelFunc
+		dialCtx, dialCancel = context.WithTimeout(ctx, dialTimeout)
+		defer dialCancel()
+	}
+
+	conn, err := dialContext(dialCtx, dialer, net.JoinHostPort(addr.String(), strconv.FormatUint(port, 10)))
+	if err != nil {
+		return dialMultiSubnetFailoverResult{err: err}
+	}
+
+	if err := preloginHandshake(dialCtx, conn); err != nil {
+		conn.Close()
+		return dialMultiSubnetFailoverResult{err: err}
+	}
+
+	return dialMultiSubnetFailoverResult{conn: conn}
+}
+
+// drainMultiSubnetFailoverResults closes connections from racers that were
+// still in flight when a winner was already chosen.
+func drainMultiSubnetFailoverResults(results <-chan dialMultiSubnetFailoverResult, n int) {
+	for i := 0; i < n; i++ {
+		if r := <-results; r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
+
+// dialContext dials addr with dialer if set, falling back to a plain
+// net.Dialer otherwise.
+func dialContext(ctx context.Context, dialer Dialer, addr string) (net.Conn, error) {
+	if dialer != nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
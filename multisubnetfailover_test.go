@@ -0,0 +1,259 @@
+package mssql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConn is a minimal net.Conn that tracks whether it was closed. Conn is
+// nil for tests that fake out preloginHandshake entirely; it is a real
+// net.Conn (typically one half of a net.Pipe) for tests that exercise
+// realPreloginHandshake itself, so Read/Write/SetDeadline behave like a real
+// connection.
+type fakeConn struct {
+	net.Conn
+	addr   string
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.Conn != nil {
+		return c.Conn.Close()
+	}
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// fakeResolver implements Resolver by returning a fixed address list.
+type fakeResolver struct {
+	addrs []net.IPAddr
+}
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r.addrs, nil
+}
+
+// fakeDialer implements Dialer, handing out a fakeConn per address after an
+// optional per-address delay, so tests can control which TCP handshake
+// "finishes" first independently of which PRELOGIN exchange succeeds. Every
+// fakeConn it creates is recorded in conns so the test can assert on the
+// close state of every dial attempt, winner and losers alike.
+type fakeDialer struct {
+	delay map[string]time.Duration
+
+	mu    sync.Mutex
+	conns map[string]*fakeConn
+}
+
+func (d *fakeDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if delay, ok := d.delay[addr]; ok {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	fc := &fakeConn{addr: addr}
+	d.mu.Lock()
+	if d.conns == nil {
+		d.conns = map[string]*fakeConn{}
+	}
+	d.conns[addr] = fc
+	d.mu.Unlock()
+	return fc, nil
+}
+
+func TestDialMultiSubnetFailoverWinnerIsPreloginAck(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("10.0.0.1")},
+		{IP: net.ParseIP("10.0.0.2")},
+		{IP: net.ParseIP("10.0.0.3")},
+	}
+	const port = 1433
+	winningAddr := net.JoinHostPort("10.0.0.3", "1433")
+
+	dialer := &fakeDialer{
+		// The winning address dials slowest, so it must win on PRELOGIN
+		// acknowledgement rather than on being first to establish TCP.
+		delay: map[string]time.Duration{
+			winningAddr: 20 * time.Millisecond,
+		},
+	}
+
+	origHandshake := preloginHandshake
+	defer func() { preloginHandshake = origHandshake }()
+
+	preloginHandshake = func(ctx context.Context, conn net.Conn) error {
+		fc := conn.(*fakeConn)
+		if fc.addr != winningAddr {
+			return errors.New("fake prelogin rejected")
+		}
+		return nil
+	}
+
+	conn, err := dialMultiSubnetFailover(context.Background(), fakeResolver{addrs: addrs}, dialer, "ag-listener", port, time.Second)
+	if err != nil {
+		t.Fatalf("dialMultiSubnetFailover returned error: %v", err)
+	}
+	defer conn.Close()
+
+	fc, ok := conn.(*fakeConn)
+	if !ok {
+		t.Fatalf("unexpected conn type %T", conn)
+	}
+	if fc.addr != winningAddr {
+		t.Fatalf("winning address = %s, want %s", fc.addr, winningAddr)
+	}
+	if fc.isClosed() {
+		t.Fatal("winning connection must not be closed")
+	}
+
+	dialer.mu.Lock()
+	dialed := make(map[string]*fakeConn, len(dialer.conns))
+	for addr, c := range dialer.conns {
+		dialed[addr] = c
+	}
+	dialer.mu.Unlock()
+
+	if len(dialed) != len(addrs) {
+		t.Fatalf("expected %d dial attempts, got %d", len(addrs), len(dialed))
+	}
+	for addr, c := range dialed {
+		if addr == winningAddr {
+			continue
+		}
+		if !c.isClosed() {
+			t.Errorf("losing connection to %s was not closed", addr)
+		}
+	}
+}
+
+func TestDialMultiSubnetFailoverAllFail(t *testing.T) {
+	addrs := []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}
+
+	origHandshake := preloginHandshake
+	defer func() { preloginHandshake = origHandshake }()
+	preloginHandshake = func(ctx context.Context, conn net.Conn) error {
+		return errors.New("fake prelogin rejected")
+	}
+
+	_, err := dialMultiSubnetFailover(context.Background(), fakeResolver{addrs: addrs}, &fakeDialer{}, "ag-listener", 1433, time.Second)
+	if err == nil {
+		t.Fatal("expected an error when every address fails, got nil")
+	}
+}
+
+// handshakeFakeDialer hands out real net.Pipe-backed connections, unlike
+// fakeDialer's instant stub conns, so a losing racer actually blocks inside
+// realPreloginHandshake's io.ReadFull the way a stalled real connection
+// would. The winning address gets a fake server that completes the PRELOGIN
+// exchange; every other address gets a fake server that reads the request
+// but never replies.
+type handshakeFakeDialer struct {
+	winningAddr string
+
+	mu    sync.Mutex
+	conns map[string]*fakeConn
+}
+
+func (d *handshakeFakeDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	if addr == d.winningAddr {
+		go respondToFakePrelogin(server)
+	} else {
+		go io.Copy(io.Discard, server)
+	}
+
+	fc := &fakeConn{Conn: client, addr: addr}
+	d.mu.Lock()
+	if d.conns == nil {
+		d.conns = map[string]*fakeConn{}
+	}
+	d.conns[addr] = fc
+	d.mu.Unlock()
+	return fc, nil
+}
+
+// respondToFakePrelogin reads a PRELOGIN request off conn and replies with a
+// minimal well-formed acknowledgement, playing the server side of the
+// exchange realPreloginHandshake drives from the client side.
+func respondToFakePrelogin(conn net.Conn) {
+	header := make([]byte, tdsPacketHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	payload := make([]byte, length-tdsPacketHeaderSize)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return
+	}
+
+	ackHeader := make([]byte, tdsPacketHeaderSize)
+	ackHeader[0] = tdsPacketTypeTabularResult
+	ackHeader[1] = tdsPacketStatusEOM
+	versionData := []byte{0, 0, 0, 0, 0, 0}
+	ackBody := []byte{preloginOptionVersion, 0, 6, 0, byte(len(versionData)), preloginOptionTerminator}
+	ackBody = append(ackBody, versionData...)
+	binary.BigEndian.PutUint16(ackHeader[2:4], uint16(tdsPacketHeaderSize+len(ackBody)))
+
+	conn.Write(append(ackHeader, ackBody...))
+}
+
+// TestDialMultiSubnetFailoverCancelsInFlightLosers exercises the real
+// realPreloginHandshake, not a fake, against a losing racer that dials
+// successfully but never receives a PRELOGIN reply - the case the
+// wrong-address-rejection fakes used elsewhere in this file can't reach.
+// Once dialMultiSubnetFailover picks a winner, the losing connection must be
+// closed promptly instead of staying blocked until its own dial_timeout.
+func TestDialMultiSubnetFailoverCancelsInFlightLosers(t *testing.T) {
+	winningAddr := net.JoinHostPort("10.0.0.2", "1433")
+	losingAddr := net.JoinHostPort("10.0.0.1", "1433")
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("10.0.0.1")},
+		{IP: net.ParseIP("10.0.0.2")},
+	}
+
+	origHandshake := preloginHandshake
+	defer func() { preloginHandshake = origHandshake }()
+	preloginHandshake = realPreloginHandshake
+
+	dialer := &handshakeFakeDialer{winningAddr: winningAddr}
+
+	conn, err := dialMultiSubnetFailover(context.Background(), fakeResolver{addrs: addrs}, dialer, "ag-listener", 1433, 5*time.Second)
+	if err != nil {
+		t.Fatalf("dialMultiSubnetFailover returned error: %v", err)
+	}
+	defer conn.Close()
+
+	dialer.mu.Lock()
+	loser := dialer.conns[losingAddr]
+	dialer.mu.Unlock()
+	if loser == nil {
+		t.Fatal("no connection recorded for the losing address")
+	}
+
+	deadline := time.After(time.Second)
+	for !loser.isClosed() {
+		select {
+		case <-deadline:
+			t.Fatal("losing connection blocked in PRELOGIN read was not closed promptly after a winner was chosen")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
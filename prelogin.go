@@ -0,0 +1,165 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Minimal subset of the MS-TDS PRELOGIN packet format needed to confirm the
+// server has acknowledged the connection. Production login negotiates many
+// more option tokens (INSTOPT, THREADID, MARS, ...); this intentionally only
+// sends/reads enough to drive the PRELOGIN handshake described in the
+// MultiSubnetFailover race and the regular single-address dial path.
+const (
+	tdsPacketTypePrelogin      byte = 0x12
+	tdsPacketTypeTabularResult byte = 0x04
+	tdsPacketStatusEOM         byte = 0x01
+	tdsPacketHeaderSize             = 8
+
+	preloginOptionVersion    byte = 0x00
+	preloginOptionEncryption byte = 0x01
+	preloginOptionTerminator byte = 0xff
+)
+
+// aLongTimeAgo is far enough in the past that setting it as a net.Conn
+// deadline aborts any in-flight Read/Write immediately, the same trick
+// net/http uses to interrupt a blocking I/O call from another goroutine.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// realPreloginHandshake sends a minimal PRELOGIN packet and reads the
+// server's response, returning an error unless the server acknowledges the
+// connection with a well-formed PRELOGIN reply. It is the production value
+// of the preloginHandshake variable used by both the single-address dial
+// path and the MultiSubnetFailover parallel-connect race.
+//
+// A net.Conn has no way to observe ctx being cancelled while it is blocked
+// inside Read or Write, so a watcher goroutine forces any blocked I/O to
+// return immediately by moving the deadline into the past the moment ctx is
+// done - not just once, up front, from ctx.Deadline(). Without this, a
+// MultiSubnetFailover racer that loses after it has already dialed but
+// before the server replies would stay blocked in io.ReadFull until its own
+// per-attempt dial_timeout elapsed, long after a winner was chosen.
+func realPreloginHandshake(ctx context.Context, conn net.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	// The deferred wait for watcherExited keeps the watcher from outliving
+	// this call, so cancelling ctx after a successful handshake cannot move
+	// the deadline of the connection handed back to the caller.
+	done := make(chan struct{})
+	watcherExited := make(chan struct{})
+	defer func() {
+		close(done)
+		<-watcherExited
+	}()
+	go func() {
+		defer close(watcherExited)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+
+	wrap := func(op string, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return fmt.Errorf("mssql: %s: %w", op, err)
+	}
+
+	if _, err := conn.Write(encodePreloginPacket()); err != nil {
+		return wrap("sending PRELOGIN", err)
+	}
+
+	header := make([]byte, tdsPacketHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return wrap("reading PRELOGIN response header", err)
+	}
+	if header[0] != tdsPacketTypeTabularResult {
+		return fmt.Errorf("mssql: PRELOGIN not acknowledged: unexpected packet type 0x%02x", header[0])
+	}
+
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	payloadLen := length - tdsPacketHeaderSize
+	if payloadLen <= 0 {
+		return fmt.Errorf("mssql: PRELOGIN response has no payload")
+	}
+
+	payload := make([]byte, payloadLen)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return wrap("reading PRELOGIN response payload", err)
+	}
+
+	if _, ok := decodePreloginOptions(payload)[preloginOptionVersion]; !ok {
+		return fmt.Errorf("mssql: PRELOGIN response missing VERSION option")
+	}
+
+	return nil
+}
+
+// encodePreloginPacket builds a TDS PRELOGIN request carrying a VERSION
+// option (driver version, unused by this minimal build) and an ENCRYPTION
+// option (ENCRYPT_NOT_SUP, since TLS upgrade here is handled independently
+// via upgradeToTLS rather than negotiated inside PRELOGIN).
+func encodePreloginPacket() []byte {
+	const (
+		optionCount  = 2 // VERSION, ENCRYPTION
+		metadataSize = optionCount*5 + 1
+	)
+
+	versionData := []byte{0, 0, 0, 0, 0, 0}
+	encryptionData := []byte{0x02} // ENCRYPT_NOT_SUP
+
+	var body bytes.Buffer
+	offset := metadataSize
+
+	body.WriteByte(preloginOptionVersion)
+	binary.Write(&body, binary.BigEndian, uint16(offset))
+	binary.Write(&body, binary.BigEndian, uint16(len(versionData)))
+	offset += len(versionData)
+
+	body.WriteByte(preloginOptionEncryption)
+	binary.Write(&body, binary.BigEndian, uint16(offset))
+	binary.Write(&body, binary.BigEndian, uint16(len(encryptionData)))
+	offset += len(encryptionData)
+
+	body.WriteByte(preloginOptionTerminator)
+	body.Write(versionData)
+	body.Write(encryptionData)
+
+	header := make([]byte, tdsPacketHeaderSize)
+	header[0] = tdsPacketTypePrelogin
+	header[1] = tdsPacketStatusEOM
+	binary.BigEndian.PutUint16(header[2:4], uint16(tdsPacketHeaderSize+body.Len()))
+
+	return append(header, body.Bytes()...)
+}
+
+// decodePreloginOptions parses the option-token metadata array at the start
+// of a PRELOGIN packet payload into a map of option token to its data slice.
+func decodePreloginOptions(payload []byte) map[byte][]byte {
+	options := map[byte][]byte{}
+
+	for i := 0; i+5 <= len(payload); i += 5 {
+		token := payload[i]
+		if token == preloginOptionTerminator {
+			break
+		}
+		offset := int(binary.BigEndian.Uint16(payload[i+1 : i+3]))
+		size := int(binary.BigEndian.Uint16(payload[i+3 : i+5]))
+		if offset < 0 || offset+size > len(payload) {
+			continue
+		}
+		options[token] = payload[offset : offset+size]
+	}
+
+	return options
+}
@@ -0,0 +1,162 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// BackoffStrategy controls how the delay between retry attempts grows.
+type BackoffStrategy int
+
+const (
+	// BackoffConstant retries after the same interval every time.
+	BackoffConstant BackoffStrategy = iota
+	// BackoffExponential doubles the interval after every attempt, capped
+	// by maxRetryInterval.
+	BackoffExponential
+)
+
+// maxRetryInterval caps the delay between retries regardless of backoff
+// strategy, so a misconfigured or very deep retry count can't stall a
+// query for an unreasonable amount of time.
+const maxRetryInterval = 30 * time.Second
+
+// Policy configures the driver's opt-in transient error retry behavior. The
+// zero value disables retries.
+type Policy struct {
+	// MaxRetries is the number of times a statement is re-executed after a
+	// transient failure. 0 disables retries.
+	MaxRetries int
+	// Interval is the base delay between attempts.
+	Interval time.Duration
+	// Backoff controls how Interval grows across attempts.
+	Backoff BackoffStrategy
+	// RetryTxn allows retrying when the caller already has a
+	// user-visible transaction in progress. Off by default, since the
+	// driver cannot know whether earlier statements in that transaction
+	// are themselves safe to replay.
+	RetryTxn bool
+}
+
+// policyFromConnectParams builds the default Policy carried by a connection,
+// derived from the retrycount/retryinterval/retrybackoff/retrytxn DSN
+// parameters or their Config equivalents.
+func policyFromConnectParams(p connectParams) Policy {
+	return Policy{
+		MaxRetries: p.retryCount,
+		Interval:   p.retryInterval,
+		Backoff:    p.retryBackoff,
+		RetryTxn:   p.retryTxn,
+	}
+}
+
+type retryPolicyContextKey struct{}
+
+// WithRetryPolicy returns a copy of ctx carrying policy, overriding whatever
+// retry behavior the connection would otherwise use for the query or
+// transaction run with that context.
+func WithRetryPolicy(ctx context.Context, policy Policy) context.Context {
+	return context.WithValue(ctx, retryPolicyContextKey{}, policy)
+}
+
+// retryPolicyFromContext returns the Policy attached to ctx by
+// WithRetryPolicy, falling back to def when none is set.
+func retryPolicyFromContext(ctx context.Context, def Policy) Policy {
+	if policy, ok := ctx.Value(retryPolicyContextKey{}).(Policy); ok {
+		return policy
+	}
+	return def
+}
+
+// transientErrorNumbers are the SQL Server error numbers known to be safe to
+// retry: deadlock victims, snapshot isolation conflicts, lock resource
+// exhaustion, and the Azure SQL throttling/failover codes.
+var transientErrorNumbers = map[int32]bool{
+	1205:  true, // deadlock victim
+	3960:  true, // snapshot isolation update conflict
+	1204:  true, // the instance ran out of lock resources
+	40197: true, // Azure SQL: service encountered an error
+	40501: true, // Azure SQL: service is busy
+	40613: true, // Azure SQL: database unavailable
+	49918: true, // Azure SQL: cannot process request, not enough resources
+	49919: true, // Azure SQL: cannot process create/update request, too many operations in progress
+	49920: true, // Azure SQL: cannot process request, too many operations in progress
+}
+
+// RetryableError reports whether err represents a transient failure: a known
+// transient mssql.Error, a *net.OpError, or io.EOF. It looks at err alone, so
+// a network error or EOF is reported as retryable whether or not part of the
+// response had already been streamed to the caller.
+func RetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var sqlErr Error
+	if errors.As(err, &sqlErr) {
+		return transientErrorNumbers[sqlErr.Number]
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF)
+}
+
+// nextRetryInterval returns the delay to sleep before attempt (0-based),
+// applying the configured backoff strategy and capping at maxRetryInterval.
+func nextRetryInterval(policy Policy, attempt int) time.Duration {
+	interval := policy.Interval
+	if interval <= 0 {
+		return 0
+	}
+
+	if policy.Backoff == BackoffExponential {
+		for i := 0; i < attempt; i++ {
+			interval *= 2
+			if interval >= maxRetryInterval {
+				return maxRetryInterval
+			}
+		}
+	}
+
+	if interval > maxRetryInterval {
+		return maxRetryInterval
+	}
+	return interval
+}
+
+// withRetry runs fn, re-running it up to policy.MaxRetries times while
+// RetryableError(err) holds, sleeping nextRetryInterval between attempts and
+// bailing out early if ctx is done. It is used by the Query/Exec paths to
+// transparently recover from transient SQL Server errors; callers there are
+// responsible for only invoking it when no rows have yet been consumed by
+// the caller and, unless policy.RetryTxn is set, no user-visible transaction
+// is already in progress.
+func withRetry(ctx context.Context, policy Policy, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= policy.MaxRetries || !RetryableError(err) {
+			return err
+		}
+
+		delay := nextRetryInterval(policy, attempt)
+		if delay <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRetryableError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"deadlock victim", Error{Number: 1205}, true},
+		{"snapshot conflict", Error{Number: 3960}, true},
+		{"non-transient sql error", Error{Number: 547}, false},
+		{"net op error", &net.OpError{Op: "dial", Err: errors.New("boom")}, true},
+		{"eof", io.EOF, true},
+		{"wrapped eof", fmtErrorf(io.EOF), true},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RetryableError(tc.err); got != tc.want {
+				t.Errorf("RetryableError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func fmtErrorf(err error) error {
+	return &wrappedError{err: err}
+}
+
+type wrappedError struct{ err error }
+
+func (w *wrappedError) Error() string { return "wrapped: " + w.err.Error() }
+func (w *wrappedError) Unwrap() error { return w.err }
+
+func TestNextRetryInterval(t *testing.T) {
+	constant := Policy{Interval: 100 * time.Millisecond, Backoff: BackoffConstant}
+	if got := nextRetryInterval(constant, 0); got != 100*time.Millisecond {
+		t.Errorf("constant attempt 0 = %v, want 100ms", got)
+	}
+	if got := nextRetryInterval(constant, 5); got != 100*time.Millisecond {
+		t.Errorf("constant attempt 5 = %v, want 100ms", got)
+	}
+
+	exp := Policy{Interval: 100 * time.Millisecond, Backoff: BackoffExponential}
+	if got := nextRetryInterval(exp, 0); got != 100*time.Millisecond {
+		t.Errorf("exponential attempt 0 = %v, want 100ms", got)
+	}
+	if got := nextRetryInterval(exp, 1); got != 200*time.Millisecond {
+		t.Errorf("exponential attempt 1 = %v, want 200ms", got)
+	}
+	if got := nextRetryInterval(exp, 10); got != maxRetryInterval {
+		t.Errorf("exponential attempt 10 = %v, want cap %v", got, maxRetryInterval)
+	}
+}
+
+func TestWithRetryStopsOnNonTransientError(t *testing.T) {
+	attempts := 0
+	err := withRetry(context.Background(), Policy{MaxRetries: 3, Interval: time.Millisecond}, func() error {
+		attempts++
+		return errors.New("permanent failure")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (no retry for a non-transient error)", attempts)
+	}
+}
+
+func TestWithRetryRetriesTransientErrorUntilSuccess(t *testing.T) {
+	attempts := 0
+	err := withRetry(context.Background(), Policy{MaxRetries: 3, Interval: time.Millisecond}, func() error {
+		attempts++
+		if attempts < 3 {
+			return Error{Number: 1205}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestWithRetryGivesUpAfterMaxRetries(t *testing.T) {
+	attempts := 0
+	err := withRetry(context.Background(), Policy{MaxRetries: 2, Interval: time.Millisecond}, func() error {
+		attempts++
+		return Error{Number: 1205}
+	})
+	if err == nil {
+		t.Fatal("expected the last transient error to be returned")
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3 (1 initial + 2 retries)", attempts)
+	}
+}
+
+func TestWithRetryStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := withRetry(ctx, Policy{MaxRetries: 5, Interval: time.Hour}, func() error {
+		attempts++
+		return Error{Number: 1205}
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (cancelled context should stop before the first sleep completes)", attempts)
+	}
+}
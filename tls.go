@@ -0,0 +1,107 @@
+package mssql
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// tlsConfigRegisterLock protects tlsConfigRegister from concurrent access.
+var tlsConfigRegisterLock sync.Mutex
+
+// tlsConfigRegister keeps the TLS configs registered with RegisterTLSConfig.
+var tlsConfigRegister map[string]*tls.Config
+
+// RegisterTLSConfig registers a custom tls.Config to be used with the `tls`
+// DSN parameter. Use the registered name as the value of the `tls` parameter,
+// e.g. `tls=custom` after calling RegisterTLSConfig("custom", cfg).
+//
+// Registered configs are shared across all DSNs that reference them, so
+// callers can wire up mutual TLS, a custom root pool, pinned certificates or
+// a FIPS-only cipher suite once and reuse it everywhere.
+func RegisterTLSConfig(name string, cfg *tls.Config) error {
+	if strings.EqualFold(name, "true") || strings.EqualFold(name, "false") || strings.EqualFold(name, "skip-verify") {
+		return fmt.Errorf("mssql: tls config name '%s' is reserved", name)
+	}
+
+	tlsConfigRegisterLock.Lock()
+	if tlsConfigRegister == nil {
+		tlsConfigRegister = make(map[string]*tls.Config)
+	}
+	tlsConfigRegister[name] = cfg
+	tlsConfigRegisterLock.Unlock()
+	return nil
+}
+
+// DeregisterTLSConfig removes the tls.Config registered under name. It is a
+// no-op if no config was registered under that name.
+func DeregisterTLSConfig(name string) {
+	tlsConfigRegisterLock.Lock()
+	if tlsConfigRegister != nil {
+		delete(tlsConfigRegister, name)
+	}
+	tlsConfigRegisterLock.Unlock()
+}
+
+// getTLSConfig looks up a tls.Config previously registered with
+// RegisterTLSConfig. The returned bool reports whether a config was found.
+func getTLSConfig(name string) (*tls.Config, bool) {
+	tlsConfigRegisterLock.Lock()
+	defer tlsConfigRegisterLock.Unlock()
+	if tlsConfigRegister == nil {
+		return nil, false
+	}
+	cfg, ok := tlsConfigRegister[name]
+	return cfg, ok
+}
+
+// upgradeToTLS wraps conn in a client-side TLS connection and completes the
+// handshake. An explicit tlsConfig (set via the `tls` DSN parameter plus
+// RegisterTLSConfig, or Config.TLSConfig) always takes precedence; only when
+// none was supplied is a config built from
+// certificate/hostInCertificate/trustServerCertificate.
+func upgradeToTLS(ctx context.Context, conn net.Conn, p connectParams) (net.Conn, error) {
+	cfg := p.tlsConfig
+	if cfg == nil {
+		built, err := buildTLSConfigFromParams(p)
+		if err != nil {
+			return nil, err
+		}
+		cfg = built
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("mssql: TLS handshake: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// buildTLSConfigFromParams builds a tls.Config the way this driver always
+// did before the `tls` DSN parameter existed: from the certificate,
+// hostInCertificate and trustServerCertificate connection parameters.
+func buildTLSConfigFromParams(p connectParams) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         p.hostInCertificate,
+		InsecureSkipVerify: p.trustServerCertificate,
+	}
+
+	if p.certificate != "" {
+		pem, err := os.ReadFile(p.certificate)
+		if err != nil {
+			return nil, fmt.Errorf("mssql: reading certificate '%s': %w", p.certificate, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mssql: no certificates found in '%s'", p.certificate)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}